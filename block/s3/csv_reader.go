@@ -0,0 +1,176 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// csvReader implements InventoryFileReader/InventoryMetadataReader over a
+// gzipped CSV inventory file, AWS's default S3 Inventory output format.
+// CSV inventory files carry no header row, so the column order is taken
+// from the manifest's fileSchema, and no row index exists, so SkipRows is
+// implemented by counting decoded records rather than seeking.
+type csvReader struct {
+	body    []byte
+	columns []string
+	records *csv.Reader
+	gz      *gzip.Reader
+
+	minMaxScanned bool
+	minValue      string
+	maxValue      string
+}
+
+func newCsvReader(ctx context.Context, svc s3iface.S3API, m *Manifest, key string, sse *sseCustomerKeyConfig) (*csvReader, error) {
+	body, err := getInventoryObject(ctx, svc, m, key, sse)
+	if err != nil {
+		return nil, err
+	}
+	gz, records, err := newCsvRecordReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open csv inventory file %s: %w", key, err)
+	}
+	return &csvReader{body: body, columns: parseManifestSchema(m.FileSchema), records: records, gz: gz}, nil
+}
+
+func newCsvRecordReader(body []byte) (*gzip.Reader, *csv.Reader, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	records := csv.NewReader(gz)
+	records.FieldsPerRecord = -1
+	return gz, records, nil
+}
+
+func (c *csvReader) GetNumRows() int64 {
+	gz, records, err := newCsvRecordReader(c.body)
+	if err != nil {
+		return 0
+	}
+	defer func() { _ = gz.Close() }()
+	var n int64
+	for {
+		if _, err := records.Read(); err != nil {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// SkipRows discards the next n records by decoding and dropping them, since
+// CSV inventory files have no row index to seek by.
+func (c *csvReader) SkipRows(n int64) error {
+	for i := int64(0); i < n; i++ {
+		if _, err := c.records.Read(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvReader) MinValue() string {
+	c.scanMinMax()
+	return c.minValue
+}
+
+func (c *csvReader) MaxValue() string {
+	c.scanMinMax()
+	return c.maxValue
+}
+
+func (c *csvReader) scanMinMax() {
+	if c.minMaxScanned {
+		return
+	}
+	c.minMaxScanned = true
+	keyIdx := indexOfColumn(c.columns, "key")
+	if keyIdx < 0 {
+		return
+	}
+	gz, records, err := newCsvRecordReader(c.body)
+	if err != nil {
+		return
+	}
+	defer func() { _ = gz.Close() }()
+	for {
+		record, err := records.Read()
+		if err != nil {
+			return
+		}
+		if keyIdx >= len(record) {
+			continue
+		}
+		if c.minValue == "" {
+			c.minValue = record[keyIdx]
+		}
+		c.maxValue = record[keyIdx]
+	}
+}
+
+func (c *csvReader) Read(dstInterface interface{}) error {
+	dst, ok := dstInterface.(*[]InventoryObject)
+	if !ok {
+		return fmt.Errorf("unsupported destination type: %T", dstInterface)
+	}
+	batchSize := len(*dst)
+	rows := make([]InventoryObject, 0, batchSize)
+	for len(rows) < batchSize {
+		record, err := c.records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode csv inventory record: %w", err)
+		}
+		var obj InventoryObject
+		for i, col := range c.columns {
+			if i < len(record) {
+				setInventoryObjectFieldFromString(&obj, col, record[i])
+			}
+		}
+		rows = append(rows, obj)
+	}
+	*dst = rows
+	return nil
+}
+
+func (c *csvReader) Close() error {
+	return c.gz.Close()
+}
+
+// setInventoryObjectFieldFromString assigns a single CSV cell, identified
+// by its manifest column name, onto an InventoryObject.
+func setInventoryObjectFieldFromString(o *InventoryObject, column string, value string) {
+	switch column {
+	case "bucket":
+		o.Bucket = value
+	case "key":
+		o.Key = value
+	case "size":
+		o.Size = parseInt64(value)
+	case "last_modified_date":
+		o.LastModified = parseTimestamp(value)
+	case "e_tag":
+		o.Checksum = parseString(value)
+	case "version_id":
+		o.VersionId = parseString(value)
+	case "is_latest":
+		o.IsLatest = parseBool(value)
+	case "is_delete_marker":
+		o.IsDeleteMarker = parseBool(value)
+	case "sequencer":
+		o.SequencerId = parseString(value)
+	case "storage_class":
+		o.StorageClass = parseString(value)
+	case "encryption_status":
+		o.EncryptionStatus = parseString(value)
+	}
+}
@@ -2,22 +2,40 @@ package s3
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"path"
 	"sort"
+	"strings"
+	"sync"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/treeverse/lakefs/block"
 	"github.com/treeverse/lakefs/logging"
+	"golang.org/x/sync/errgroup"
 )
 
+// sortManifestConcurrency bounds how many inventory files sortManifest
+// opens at once to read their min/max keys.
+const sortManifestConcurrency = 10
+
 const (
 	OrcFormatName     = "ORC"
 	ParquetFormatName = "Parquet"
+	CSVFormatName     = "CSV"
+
+	manifestChecksumFilename = "manifest.checksum"
 )
 
 type Manifest struct {
@@ -26,11 +44,25 @@ type Manifest struct {
 	SourceBucket       string          `json:"sourceBucket"`
 	Files              []inventoryFile `json:"files"`
 	Format             string          `json:"fileFormat"`
+	FileSchema         string          `json:"fileSchema"`
 	inventoryBucket    string
 }
 
 type inventoryFile struct {
-	Key string `json:"key"`
+	Key         string `json:"key"`
+	MD5checksum string `json:"MD5checksum"`
+}
+
+// fileChecksum returns the manifest's expected MD5 checksum for the given
+// inventory file key, or "" if the manifest doesn't carry one (e.g. it
+// predates checksums being published).
+func (m *Manifest) fileChecksum(key string) string {
+	for _, f := range m.Files {
+		if f.Key == key {
+			return f.MD5checksum
+		}
+	}
+	return ""
 }
 
 type InventoryMetadataReader interface {
@@ -48,17 +80,57 @@ type InventoryFileReader interface {
 
 type CloseFunc func() error
 
-var ErrUnsupportedInventoryFormat = errors.New("unsupported inventory type. supported types: parquet, orc")
+var (
+	ErrUnsupportedInventoryFormat = errors.New("unsupported inventory type. supported types: parquet, orc, csv")
+	ErrInventoryChecksumMismatch  = errors.New("inventory file checksum mismatch")
+)
 
 func (a *Adapter) GenerateInventory(ctx context.Context, logger logging.Logger, manifestURL string, shouldSort bool) (block.Inventory, error) {
-	return GenerateInventory(ctx, logger, manifestURL, a.s3, NewInventoryReader(ctx, a.s3, logger), shouldSort)
+	return GenerateInventory(ctx, logger, manifestURL, a.s3, a.s3, NewInventoryReader(ctx, a.s3, logger), shouldSort)
+}
+
+// GenerateInventoryWithVersions behaves like GenerateInventory but also
+// surfaces delete markers and non-latest object versions from a versioned
+// source bucket's inventory, instead of only the latest live version of
+// each key.
+func (a *Adapter) GenerateInventoryWithVersions(ctx context.Context, logger logging.Logger, manifestURL string, shouldSort bool) (block.Inventory, error) {
+	reader := NewInventoryReader(ctx, a.s3, logger, WithAllVersions())
+	return GenerateInventory(ctx, logger, manifestURL, a.s3, a.s3, reader, shouldSort)
 }
 
-func GenerateInventory(ctx context.Context, logger logging.Logger, manifestURL string, s3 s3iface.S3API, inventoryReader IInventoryReader, shouldSort bool) (block.Inventory, error) {
+// GenerateInventoryFromSource behaves like GenerateInventory, but reads the
+// manifest and its inventory files through a dedicated S3-compatible
+// client built from sourceConfig, instead of the adapter's own client.
+// This supports MinIO, Ceph RGW and other setups where the inventory is
+// hosted in a different account or endpoint than the data bucket; objects
+// referenced by the inventory are still read through the adapter's own
+// client (a.s3).
+func (a *Adapter) GenerateInventoryFromSource(ctx context.Context, logger logging.Logger, manifestURL string, shouldSort bool, sourceConfig *InventorySourceConfig, opts ...InventoryReaderOption) (block.Inventory, error) {
+	inventorySvc, err := sourceConfig.buildS3Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure inventory source: %w", err)
+	}
+	if sourceConfig.SSECustomerAlgorithm != "" {
+		opts = append(opts, WithSSECustomerKey(sourceConfig.SSECustomerAlgorithm, sourceConfig.SSECustomerKey))
+	}
+	reader := NewInventoryReader(ctx, inventorySvc, logger, opts...)
+	return GenerateInventory(ctx, logger, manifestURL, inventorySvc, a.s3, reader, shouldSort)
+}
+
+// GenerateInventory loads the manifest at manifestURL and, optionally,
+// sorts its files. inventorySvc is used to read the manifest and the
+// inventory files it lists; dataSvc is stored on the returned Inventory
+// for reading the objects the inventory references, which may live behind
+// different credentials or an entirely different endpoint.
+func GenerateInventory(ctx context.Context, logger logging.Logger, manifestURL string, inventorySvc, dataSvc s3iface.S3API, inventoryReader IInventoryReader, shouldSort bool) (block.Inventory, error) {
 	if logger == nil {
 		logger = logging.Default()
 	}
-	m, err := loadManifest(manifestURL, s3)
+	var sse *sseCustomerKeyConfig
+	if sseReader, ok := inventoryReader.(sseAware); ok {
+		sse = sseReader.sseConfig()
+	}
+	m, err := loadManifest(manifestURL, inventorySvc, sse)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +140,56 @@ func GenerateInventory(ctx context.Context, logger logging.Logger, manifestURL s
 	if err != nil {
 		return nil, err
 	}
-	return &Inventory{Manifest: m, S3: s3, ctx: ctx, logger: logger, shouldSort: shouldSort, reader: inventoryReader}, nil
+	return &Inventory{Manifest: m, S3: dataSvc, ctx: ctx, logger: logger, shouldSort: shouldSort, reader: inventoryReader}, nil
+}
+
+// InventorySourceConfig configures a dedicated S3-compatible client for
+// reading an inventory manifest and its files, separately from the
+// adapter's own client. This is needed when the inventory is hosted by a
+// different account, a non-AWS endpoint (MinIO, Ceph RGW, ...), or under
+// credentials that differ from the ones used to read the data bucket.
+type InventorySourceConfig struct {
+	Endpoint         string
+	Region           string
+	AccessKeyID      string
+	SecretAccessKey  string
+	SessionToken     string
+	S3ForcePathStyle bool
+	// SignatureVersion defaults to "v4" when empty; it is currently the
+	// only signature version this reader supports.
+	SignatureVersion string
+	// SSECustomerAlgorithm and SSECustomerKey, when set, are sent on every
+	// GetObject issued for the manifest and its inventory files, to read
+	// SSE-C-encrypted inventories (objects encrypted with a customer-
+	// supplied key). SSE-KMS-encrypted inventories need no such headers.
+	SSECustomerAlgorithm string
+	SSECustomerKey       string
+}
+
+const defaultInventorySignatureVersion = "v4"
+
+func (c *InventorySourceConfig) buildS3Client() (s3iface.S3API, error) {
+	signatureVersion := c.SignatureVersion
+	if signatureVersion == "" {
+		signatureVersion = defaultInventorySignatureVersion
+	}
+	if signatureVersion != defaultInventorySignatureVersion {
+		return nil, fmt.Errorf("unsupported inventory source signature version: %s", signatureVersion)
+	}
+	cfg := aws.NewConfig().
+		WithRegion(c.Region).
+		WithS3ForcePathStyle(c.S3ForcePathStyle)
+	if c.Endpoint != "" {
+		cfg = cfg.WithEndpoint(c.Endpoint)
+	}
+	if c.AccessKeyID != "" {
+		cfg = cfg.WithCredentials(credentials.NewStaticCredentials(c.AccessKeyID, c.SecretAccessKey, c.SessionToken))
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
 }
 
 type Inventory struct {
@@ -92,47 +213,118 @@ func (inv *Inventory) InventoryURL() string {
 	return inv.Manifest.URL
 }
 
-func loadManifest(manifestURL string, s3svc s3iface.S3API) (*Manifest, error) {
+func loadManifest(manifestURL string, s3svc s3iface.S3API, sse *sseCustomerKeyConfig) (*Manifest, error) {
 	u, err := url.Parse(manifestURL)
 	if err != nil {
 		return nil, err
 	}
-	output, err := s3svc.GetObject(&s3.GetObjectInput{Bucket: &u.Host, Key: &u.Path})
+	output, err := s3svc.GetObject(sse.apply(&s3.GetObjectInput{Bucket: &u.Host, Key: &u.Path}))
 	if err != nil {
 		return nil, err
 	}
-	var m Manifest
-	err = json.NewDecoder(output.Body).Decode(&m)
+	defer func() { _ = output.Body.Close() }()
+	body, err := ioutil.ReadAll(output.Body)
 	if err != nil {
 		return nil, err
 	}
-	if m.Format != OrcFormatName && m.Format != ParquetFormatName {
+	if err := verifyManifestChecksum(s3svc, u, body, sse); err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, err
+	}
+	if m.Format != OrcFormatName && m.Format != ParquetFormatName && m.Format != CSVFormatName {
 		return nil, fmt.Errorf("%w. got format: %s", ErrUnsupportedInventoryFormat, m.Format)
 	}
 	m.URL = manifestURL
-	inventoryBucketArn, err := arn.Parse(m.InventoryBucketArn)
+	m.inventoryBucket, err = inventoryBucketName(m.InventoryBucketArn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse inventory bucket arn: %w", err)
+		return nil, err
 	}
-	m.inventoryBucket = inventoryBucketArn.Resource
 	return &m, nil
 }
 
+// inventoryBucketName extracts the bucket name from the manifest's
+// destinationBucket field. It accepts real AWS ARNs from any partition
+// (aws, aws-cn, aws-us-gov, ...) and falls back to treating the value as a
+// bare bucket name, for S3-compatible stores whose manifests don't
+// populate an ARN there.
+func inventoryBucketName(destinationBucket string) (string, error) {
+	if destinationBucket == "" {
+		return "", errors.New("manifest is missing destinationBucket")
+	}
+	if parsedArn, err := arn.Parse(destinationBucket); err == nil {
+		return parsedArn.Resource, nil
+	}
+	return destinationBucket, nil
+}
+
+// verifyManifestChecksum fetches manifest.checksum, manifest.json's sibling
+// in the inventory bucket, and compares it against the MD5 of body - the
+// bytes just read for manifest.json itself. Like the per-file MD5checksum
+// check in getInventoryObject, this is best-effort: S3-compatible sources
+// (MinIO, Ceph RGW, ...) have no obligation to publish an AWS-style
+// manifest.checksum sibling, so a missing checksum file just skips
+// verification instead of failing manifest loading outright.
+func verifyManifestChecksum(s3svc s3iface.S3API, manifestURL *url.URL, body []byte, sse *sseCustomerKeyConfig) error {
+	checksumKey := path.Join(path.Dir(manifestURL.Path), manifestChecksumFilename)
+	output, err := s3svc.GetObject(sse.apply(&s3.GetObjectInput{Bucket: &manifestURL.Host, Key: &checksumKey}))
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil
+		}
+		return fmt.Errorf("failed to read manifest checksum: %w", err)
+	}
+	defer func() { _ = output.Body.Close() }()
+	expected, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest checksum: %w", err)
+	}
+	sum := md5.Sum(body) //nolint:gosec // not used for security purposes, only to match S3's own checksum format
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(strings.TrimSpace(string(expected)), actual) {
+		return fmt.Errorf("%w: manifest.json", ErrInventoryChecksumMismatch)
+	}
+	return nil
+}
+
 func sortManifest(ctx context.Context, m *Manifest, logger logging.Logger, reader IInventoryReader) error {
 	firstKeyByInventoryFile := make(map[string]string)
 	lastKeyByInventoryFile := make(map[string]string)
+	var mu sync.Mutex
+
+	g, groupCtx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, sortManifestConcurrency)
 	for _, f := range m.Files {
-		mr, err := reader.GetInventoryMetadataReader(m, f.Key)
-		if err != nil {
-			return fmt.Errorf("failed to sort inventory files in manifest: %w", err)
-		}
-		firstKeyByInventoryFile[f.Key] = mr.MinValue()
-		lastKeyByInventoryFile[f.Key] = mr.MaxValue()
-		err = mr.Close()
-		if err != nil {
-			logger.Errorf("failed to close inventory file. file=%s, err=%w", f, err)
-		}
+		f := f
+		g.Go(func() error {
+			select {
+			case sem <- struct{}{}:
+			case <-groupCtx.Done():
+				return groupCtx.Err()
+			}
+			defer func() { <-sem }()
+
+			mr, err := reader.GetInventoryMetadataReader(m, f.Key)
+			if err != nil {
+				return fmt.Errorf("failed to sort inventory files in manifest: %w", err)
+			}
+			minValue, maxValue := mr.MinValue(), mr.MaxValue()
+			if closeErr := mr.Close(); closeErr != nil {
+				logger.Errorf("failed to close inventory file. file=%s, err=%w", f.Key, closeErr)
+			}
+			mu.Lock()
+			firstKeyByInventoryFile[f.Key] = minValue
+			lastKeyByInventoryFile[f.Key] = maxValue
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
 	}
+
 	sort.Slice(m.Files, func(i, j int) bool {
 		return firstKeyByInventoryFile[m.Files[i].Key] < firstKeyByInventoryFile[m.Files[j].Key] ||
 			(firstKeyByInventoryFile[m.Files[i].Key] == firstKeyByInventoryFile[m.Files[j].Key] &&
@@ -0,0 +1,144 @@
+package s3
+
+import (
+	"context"
+)
+
+const (
+	// prefetchWorkers bounds how many inventory files are open and being
+	// decoded at once.
+	prefetchWorkers = 5
+	// prefetchLookahead bounds how many decoded batches per file are held
+	// in memory ahead of the consumer.
+	prefetchLookahead = 2
+	iteratorBatchSize = 1000
+)
+
+// prefetchBatch is a single decoded batch of rows from one inventory file,
+// or the error that stopped decoding it.
+type prefetchBatch struct {
+	rows []InventoryObject
+	err  error
+}
+
+// InventoryIterator walks a manifest's files, in manifest order, using a
+// bounded worker pool to open and decode files ahead of the consumer. Each
+// file's batches land on their own buffered channel, so a slow consumer
+// only ever bounds memory by prefetchWorkers*prefetchLookahead batches,
+// regardless of how many files the manifest lists.
+type InventoryIterator struct {
+	fileBatches []chan prefetchBatch
+	cancel      context.CancelFunc
+
+	fileIdx int
+	buf     []InventoryObject
+	bufIdx  int
+	current InventoryObject
+	err     error
+	closed  bool
+}
+
+func NewInventoryIterator(inv *Inventory) *InventoryIterator {
+	ctx, cancel := context.WithCancel(inv.ctx)
+	return &InventoryIterator{
+		fileBatches: prefetchManifest(ctx, inv, prefetchWorkers, prefetchLookahead),
+		cancel:      cancel,
+	}
+}
+
+// prefetchManifest launches, for every file in the manifest, a goroutine
+// that waits for a worker slot and then streams that file's decoded
+// batches onto its own channel. At most `workers` goroutines are actually
+// reading and decoding at any given time; the rest are parked waiting for
+// a slot, which is what keeps memory bounded regardless of file count.
+func prefetchManifest(ctx context.Context, inv *Inventory, workers, lookahead int) []chan prefetchBatch {
+	files := inv.Manifest.Files
+	channels := make([]chan prefetchBatch, len(files))
+	sem := make(chan struct{}, workers)
+	for i := range files {
+		channels[i] = make(chan prefetchBatch, lookahead)
+		go prefetchFile(ctx, inv, files[i].Key, channels[i], sem)
+	}
+	return channels
+}
+
+func prefetchFile(ctx context.Context, inv *Inventory, key string, out chan<- prefetchBatch, sem chan struct{}) {
+	defer close(out)
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-sem }()
+
+	fileReader, err := inv.reader.GetInventoryFileReader(inv.Manifest, key)
+	if err != nil {
+		sendBatch(ctx, out, prefetchBatch{err: err})
+		return
+	}
+	defer func() { _ = fileReader.Close() }()
+
+	for {
+		batch := make([]InventoryObject, iteratorBatchSize)
+		if err := fileReader.Read(&batch); err != nil {
+			sendBatch(ctx, out, prefetchBatch{err: err})
+			return
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if !sendBatch(ctx, out, prefetchBatch{rows: batch}) {
+			return
+		}
+	}
+}
+
+func sendBatch(ctx context.Context, out chan<- prefetchBatch, b prefetchBatch) bool {
+	select {
+	case out <- b:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (it *InventoryIterator) Next() bool {
+	if it.err != nil || it.closed {
+		return false
+	}
+	for it.bufIdx >= len(it.buf) {
+		if it.fileIdx >= len(it.fileBatches) {
+			return false
+		}
+		batch, ok := <-it.fileBatches[it.fileIdx]
+		if !ok {
+			it.fileIdx++
+			continue
+		}
+		if batch.err != nil {
+			it.err = batch.err
+			return false
+		}
+		it.buf = batch.rows
+		it.bufIdx = 0
+	}
+	it.current = it.buf[it.bufIdx]
+	it.bufIdx++
+	return true
+}
+
+func (it *InventoryIterator) Get() *InventoryObject {
+	return &it.current
+}
+
+func (it *InventoryIterator) Err() error {
+	return it.err
+}
+
+func (it *InventoryIterator) Close() {
+	if it.closed {
+		return
+	}
+	it.closed = true
+	it.cancel()
+}
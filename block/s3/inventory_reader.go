@@ -0,0 +1,354 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/treeverse/lakefs/logging"
+)
+
+// InventoryObject represents a single row of an S3 Inventory report. Only
+// the fields present in the manifest's fileSchema are populated; everything
+// else is left nil.
+type InventoryObject struct {
+	Bucket           string
+	Key              string
+	Size             *int64
+	LastModified     *int64
+	Checksum         *string
+	VersionId        *string
+	IsLatest         *bool
+	IsDeleteMarker   *bool
+	SequencerId      *string
+	StorageClass     *string
+	EncryptionStatus *string
+}
+
+// defaultInventoryColumns is the column set of the legacy, unversioned
+// inventory schema, used when the manifest does not carry a fileSchema.
+var defaultInventoryColumns = []string{"bucket", "key", "size", "last_modified_date", "e_tag"}
+
+// manifestColumnNames maps the PascalCase column names a real manifest.json
+// publishes in fileSchema (e.g. "VersionId", "LastModifiedDate", "ETag") to
+// the snake_case keys setInventoryObjectField and
+// setInventoryObjectFieldFromString switch on, which in turn match the
+// column names AWS actually embeds in the ORC/Parquet/CSV inventory files
+// themselves. Keyed by the lowercased column name with no separators, so
+// the lookup is case-insensitive and ignores how parseManifestSchema got
+// there.
+var manifestColumnNames = map[string]string{
+	"bucket":           "bucket",
+	"key":              "key",
+	"versionid":        "version_id",
+	"islatest":         "is_latest",
+	"isdeletemarker":   "is_delete_marker",
+	"size":             "size",
+	"lastmodifieddate": "last_modified_date",
+	"etag":             "e_tag",
+	"sequencer":        "sequencer",
+	"storageclass":     "storage_class",
+	"encryptionstatus": "encryption_status",
+}
+
+// parseManifestSchema splits the manifest's fileSchema field (a
+// comma-separated, possibly whitespace-padded list of column names) into
+// its ordered column list, translating AWS's PascalCase column names
+// (e.g. "LastModifiedDate") into the snake_case names used elsewhere. It
+// falls back to defaultInventoryColumns when the manifest predates the
+// fileSchema field, and passes any column it doesn't recognize through
+// unchanged so already-snake_case schemas keep working.
+func parseManifestSchema(fileSchema string) []string {
+	if strings.TrimSpace(fileSchema) == "" {
+		return defaultInventoryColumns
+	}
+	rawColumns := strings.Split(fileSchema, ",")
+	columns := make([]string, len(rawColumns))
+	for i, c := range rawColumns {
+		lower := strings.ToLower(strings.TrimSpace(c))
+		if mapped, ok := manifestColumnNames[strings.ReplaceAll(lower, "_", "")]; ok {
+			columns[i] = mapped
+		} else {
+			columns[i] = lower
+		}
+	}
+	return columns
+}
+
+// IInventoryReader opens readers for individual files that belong to an
+// inventory manifest.
+type IInventoryReader interface {
+	GetInventoryMetadataReader(m *Manifest, inventoryFileKey string) (InventoryMetadataReader, error)
+	GetInventoryFileReader(m *Manifest, inventoryFileKey string) (InventoryFileReader, error)
+}
+
+// InventoryReaderOption configures an InventoryReader.
+type InventoryReaderOption func(*InventoryReader)
+
+// WithAllVersions makes GetInventoryFileReader emit every row of the
+// inventory, including delete markers and non-latest object versions.
+// Without it, the reader mirrors an unversioned bucket listing: only the
+// latest, non-deleted version of each key is returned.
+func WithAllVersions() InventoryReaderOption {
+	return func(r *InventoryReader) { r.includeAllVersions = true }
+}
+
+// WithSSECustomerKey configures the reader to send SSECustomerAlgorithm/
+// SSECustomerKey on every GetObject it issues for the manifest and its
+// inventory files, so SSE-C-encrypted inventories (objects encrypted with
+// a customer-supplied key) can be read. SSE-KMS-encrypted inventories need
+// no such headers: S3 decrypts them transparently given kms:Decrypt
+// permission on the CMK, and sending SSE-C headers against an SSE-KMS
+// object fails the GetObject call with InvalidArgument.
+func WithSSECustomerKey(algorithm, key string) InventoryReaderOption {
+	return func(r *InventoryReader) { r.sse = &sseCustomerKeyConfig{Algorithm: algorithm, Key: key} }
+}
+
+// InventoryReader is the default IInventoryReader, backed by S3 GetObject
+// calls against the inventory bucket.
+type InventoryReader struct {
+	ctx                context.Context
+	s3                 s3iface.S3API
+	logger             logging.Logger
+	includeAllVersions bool
+	sse                *sseCustomerKeyConfig
+}
+
+// sseConfig implements the unexported sseAware interface, letting
+// GenerateInventory reuse the reader's SSE-C settings when it fetches
+// manifest.json and manifest.checksum directly.
+func (r *InventoryReader) sseConfig() *sseCustomerKeyConfig {
+	return r.sse
+}
+
+func NewInventoryReader(ctx context.Context, s3 s3iface.S3API, logger logging.Logger, opts ...InventoryReaderOption) IInventoryReader {
+	r := &InventoryReader{ctx: ctx, s3: s3, logger: logger}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *InventoryReader) GetInventoryMetadataReader(m *Manifest, inventoryFileKey string) (InventoryMetadataReader, error) {
+	switch m.Format {
+	case OrcFormatName:
+		return newOrcReader(r.ctx, r.s3, m, inventoryFileKey, r.sse)
+	case ParquetFormatName:
+		return newParquetReader(r.ctx, r.s3, m, inventoryFileKey, r.sse)
+	case CSVFormatName:
+		return newCsvReader(r.ctx, r.s3, m, inventoryFileKey, r.sse)
+	default:
+		return nil, fmt.Errorf("%w. got format: %s", ErrUnsupportedInventoryFormat, m.Format)
+	}
+}
+
+func (r *InventoryReader) GetInventoryFileReader(m *Manifest, inventoryFileKey string) (InventoryFileReader, error) {
+	var (
+		fileReader InventoryFileReader
+		err        error
+	)
+	switch m.Format {
+	case OrcFormatName:
+		fileReader, err = newOrcReader(r.ctx, r.s3, m, inventoryFileKey, r.sse)
+	case ParquetFormatName:
+		fileReader, err = newParquetReader(r.ctx, r.s3, m, inventoryFileKey, r.sse)
+	case CSVFormatName:
+		fileReader, err = newCsvReader(r.ctx, r.s3, m, inventoryFileKey, r.sse)
+	default:
+		return nil, fmt.Errorf("%w. got format: %s", ErrUnsupportedInventoryFormat, m.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.includeAllVersions {
+		return fileReader, nil
+	}
+	return &latestVersionFileReader{InventoryFileReader: fileReader}, nil
+}
+
+// latestVersionFileReader wraps an InventoryFileReader and drops delete
+// markers and non-latest object versions from every batch it reads, so
+// callers that don't care about versioning see the same rows they would
+// from an unversioned bucket's inventory.
+type latestVersionFileReader struct {
+	InventoryFileReader
+}
+
+func (r *latestVersionFileReader) Read(dstInterface interface{}) error {
+	dst, ok := dstInterface.(*[]InventoryObject)
+	if !ok {
+		return fmt.Errorf("unsupported destination type: %T", dstInterface)
+	}
+	batchSize := len(*dst)
+	for {
+		batch := make([]InventoryObject, batchSize)
+		err := r.InventoryFileReader.Read(&batch)
+		filtered := batch[:0]
+		for _, o := range batch {
+			if o.IsDeleteMarker != nil && *o.IsDeleteMarker {
+				continue
+			}
+			if o.IsLatest != nil && !*o.IsLatest {
+				continue
+			}
+			filtered = append(filtered, o)
+		}
+		*dst = filtered
+		if err != nil || len(filtered) > 0 || len(batch) == 0 {
+			return err
+		}
+	}
+}
+
+// setInventoryObjectField assigns a single decoded column value, identified
+// by its manifest column name, onto an InventoryObject.
+func setInventoryObjectField(o *InventoryObject, column string, value interface{}) {
+	switch column {
+	case "bucket":
+		o.Bucket, _ = value.(string)
+	case "key":
+		o.Key, _ = value.(string)
+	case "size":
+		o.Size = toInt64Ptr(value)
+	case "last_modified_date":
+		o.LastModified = toInt64Ptr(value)
+	case "e_tag":
+		o.Checksum = toStringPtr(value)
+	case "version_id":
+		o.VersionId = toStringPtr(value)
+	case "is_latest":
+		o.IsLatest = toBoolPtr(value)
+	case "is_delete_marker":
+		o.IsDeleteMarker = toBoolPtr(value)
+	case "sequencer":
+		o.SequencerId = toStringPtr(value)
+	case "storage_class":
+		o.StorageClass = toStringPtr(value)
+	case "encryption_status":
+		o.EncryptionStatus = toStringPtr(value)
+	}
+}
+
+func toInt64Ptr(value interface{}) *int64 {
+	switch v := value.(type) {
+	case int64:
+		return &v
+	case int:
+		i := int64(v)
+		return &i
+	case time.Time:
+		i := v.Unix()
+		return &i
+	default:
+		return nil
+	}
+}
+
+func toStringPtr(value interface{}) *string {
+	if v, ok := value.(string); ok && v != "" {
+		return &v
+	}
+	return nil
+}
+
+func toBoolPtr(value interface{}) *bool {
+	if v, ok := value.(bool); ok {
+		return &v
+	}
+	return nil
+}
+
+func parseInt64(s string) *int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func parseBool(s string) *bool {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// csvLastModifiedDateLayout is the timestamp format AWS's CSV inventory
+// output uses for last_modified_date, e.g. "2016-11-06T00:00Z" - unlike
+// the ORC/Parquet formats, which carry it as a native timestamp/int64
+// column, CSV always encodes it as this ISO-8601 string.
+const csvLastModifiedDateLayout = "2006-01-02T15:04Z"
+
+func parseTimestamp(s string) *int64 {
+	t, err := time.Parse(csvLastModifiedDateLayout, s)
+	if err != nil {
+		return nil
+	}
+	unix := t.Unix()
+	return &unix
+}
+
+func parseString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// sseCustomerKeyConfig carries the SSE-C parameters (SSECustomerAlgorithm/
+// SSECustomerKey) needed to read manifest and inventory files that were
+// encrypted with a customer-supplied key. It has no bearing on SSE-KMS,
+// which S3 decrypts transparently as long as the caller has kms:Decrypt on
+// the CMK.
+type sseCustomerKeyConfig struct {
+	Algorithm string
+	Key       string
+}
+
+func (c *sseCustomerKeyConfig) apply(input *s3.GetObjectInput) *s3.GetObjectInput {
+	if c == nil || c.Algorithm == "" {
+		return input
+	}
+	input.SSECustomerAlgorithm = aws.String(c.Algorithm)
+	input.SSECustomerKey = aws.String(c.Key)
+	return input
+}
+
+// sseAware is implemented by IInventoryReader implementations that carry
+// SSE-C settings, so GenerateInventory can reuse them when it fetches
+// manifest.json and manifest.checksum directly.
+type sseAware interface {
+	sseConfig() *sseCustomerKeyConfig
+}
+
+// getInventoryObject downloads an inventory file's raw bytes and, if the
+// manifest published an MD5checksum for it, verifies them before returning.
+func getInventoryObject(ctx context.Context, svc s3iface.S3API, m *Manifest, key string, sse *sseCustomerKeyConfig) ([]byte, error) {
+	input := sse.apply(&s3.GetObjectInput{Bucket: aws.String(m.inventoryBucket), Key: aws.String(key)})
+	output, err := svc.GetObjectWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory file %s: %w", key, err)
+	}
+	defer func() { _ = output.Body.Close() }()
+	body, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory file %s: %w", key, err)
+	}
+	if expected := m.fileChecksum(key); expected != "" {
+		sum := md5.Sum(body) //nolint:gosec // not used for security purposes, only to match S3's own checksum format
+		actual := hex.EncodeToString(sum[:])
+		if !strings.EqualFold(expected, actual) {
+			return nil, fmt.Errorf("%w: %s", ErrInventoryChecksumMismatch, key)
+		}
+	}
+	return body, nil
+}
@@ -1,11 +1,18 @@
 package s3
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5" //nolint:gosec // not used for security purposes, only to match S3's own checksum format
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -21,9 +28,11 @@ import (
 	"github.com/johannesboyne/gofakes3/backend/s3mem"
 	"github.com/scritchley/orc"
 	"github.com/treeverse/lakefs/logging"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/writer"
 )
 
-func generateOrc(t *testing.T, objs []InventoryObject) string {
+func generateOrc(t testing.TB, objs []InventoryObject) string {
 	f, err := ioutil.TempFile("", "orctest")
 	if err != nil {
 		t.Fatal(err)
@@ -52,7 +61,56 @@ func generateOrc(t *testing.T, objs []InventoryObject) string {
 	return f.Name()
 }
 
-func getS3Fake(t *testing.T) (s3iface.S3API, *httptest.Server) {
+func generateVersionedOrc(t testing.TB, objs []InventoryObject) string {
+	f, err := ioutil.TempFile("", "orctest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	schema, err := orc.ParseSchema("struct<bucket:string,key:string,version_id:string,is_latest:boolean,is_delete_marker:boolean,size:int,last_modified_date:timestamp,e_tag:string>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := orc.NewWriter(f, orc.SetSchema(schema), orc.SetStripeTargetSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range objs {
+		err = w.Write(o.Bucket, o.Key, *o.VersionId, *o.IsLatest, *o.IsDeleteMarker, *o.Size, time.Unix(*o.LastModified, 0), *o.Checksum)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func uploadVersionedFile(t testing.TB, svc s3iface.S3API, inventoryBucket string, inventoryFilename string, destBucket string, objs []InventoryObject) {
+	localOrcFile := generateVersionedOrc(t, objs)
+	f, err := os.Open(localOrcFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	uploader := s3manager.NewUploaderWithClient(svc)
+	_, err = uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(inventoryBucket),
+		Key:    aws.String(inventoryFilename),
+		Body:   f,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getS3Fake(t testing.TB) (s3iface.S3API, *httptest.Server) {
 	backend := s3mem.New()
 	faker := gofakes3.New(backend)
 	ts := httptest.NewServer(faker.Server())
@@ -71,7 +129,7 @@ func getS3Fake(t *testing.T) (s3iface.S3API, *httptest.Server) {
 	return s3.New(newSession), ts
 }
 
-func uploadFile(t *testing.T, s3 s3iface.S3API, inventoryBucket string, inventoryFilename string, destBucket string, keys ...string) {
+func uploadFile(t testing.TB, s3 s3iface.S3API, inventoryBucket string, inventoryFilename string, destBucket string, keys ...string) {
 	objs := make([]InventoryObject, len(keys))
 	for i, k := range keys {
 		objs[i] = InventoryObject{
@@ -175,3 +233,537 @@ func TestInventoryReader(t *testing.T) {
 		}
 	}
 }
+
+// BenchmarkInventoryIterator exercises the prefetching InventoryIterator
+// across many copies of the 12500-key biggerFile.orc case, the shape of
+// import where prefetch matters most: a manifest with many sizeable parts.
+func BenchmarkInventoryIterator(b *testing.B) {
+	const numFiles = 6
+	svc, testServer := getS3Fake(b)
+	defer testServer.Close()
+	const inventoryBucketName = "bench-inventory-bucket"
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(inventoryBucketName)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	keys := make([]string, 12500)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("f%d", i)
+	}
+	filenames := make([]string, numFiles)
+	for i := range filenames {
+		filenames[i] = fmt.Sprintf("biggerFile%d.orc", i)
+		uploadFile(b, svc, inventoryBucketName, filenames[i], "data-bucket", keys...)
+	}
+	m := manifest(inventoryBucketName, filenames...)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		inv := &Inventory{
+			Manifest: m,
+			ctx:      context.Background(),
+			logger:   logging.Default(),
+			reader:   NewInventoryReader(context.Background(), svc, logging.Default()),
+		}
+		it := NewInventoryIterator(inv)
+		count := 0
+		for it.Next() {
+			count++
+		}
+		if err := it.Err(); err != nil {
+			b.Fatal(err)
+		}
+		it.Close()
+		if count != numFiles*len(keys) {
+			b.Fatalf("expected %d objects, got %d", numFiles*len(keys), count)
+		}
+	}
+}
+
+func TestInventoryIterator(t *testing.T) {
+	svc, testServer := getS3Fake(t)
+	defer testServer.Close()
+	const inventoryBucketName = "iterator-inventory-bucket"
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(inventoryBucketName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filenames := []string{"a.orc", "b.orc", "c.orc"}
+	var expected []string
+	for _, name := range filenames {
+		fileKeys := []string{name + "-1", name + "-2"}
+		uploadFile(t, svc, inventoryBucketName, name, "data-bucket", fileKeys...)
+		expected = append(expected, fileKeys...)
+	}
+	m := manifest(inventoryBucketName, filenames...)
+	inv := &Inventory{
+		Manifest: m,
+		ctx:      context.Background(),
+		logger:   logging.Default(),
+		reader:   NewInventoryReader(context.Background(), svc, logging.Default()),
+	}
+	it := NewInventoryIterator(inv)
+	defer it.Close()
+	var got []string
+	for it.Next() {
+		got = append(got, it.Get().Key)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d objects, got %d: %v", len(expected), len(got), got)
+	}
+	for i, k := range expected {
+		if got[i] != k {
+			t.Fatalf("expected manifest order to be preserved: index %d expected=%s, got=%s", i, k, got[i])
+		}
+	}
+}
+
+func TestInventoryReaderVersioned(t *testing.T) {
+	svc, testServer := getS3Fake(t)
+	defer testServer.Close()
+	const inventoryBucketName = "versioned-inventory-bucket"
+	const inventoryFilename = "versioned.orc"
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(inventoryBucketName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := []InventoryObject{
+		{Bucket: "data-bucket", Key: "foo", VersionId: swag.String("v2"), IsLatest: swag.Bool(true), IsDeleteMarker: swag.Bool(false), Size: swag.Int64(500), LastModified: swag.Int64(time.Now().Unix()), Checksum: swag.String("abc")},
+		{Bucket: "data-bucket", Key: "foo", VersionId: swag.String("v1"), IsLatest: swag.Bool(false), IsDeleteMarker: swag.Bool(false), Size: swag.Int64(500), LastModified: swag.Int64(time.Now().Unix()), Checksum: swag.String("abc")},
+		{Bucket: "data-bucket", Key: "bar", VersionId: swag.String("v3"), IsLatest: swag.Bool(true), IsDeleteMarker: swag.Bool(true), Size: swag.Int64(0), LastModified: swag.Int64(time.Now().Unix()), Checksum: swag.String("")},
+	}
+	uploadVersionedFile(t, svc, inventoryBucketName, inventoryFilename, "data-bucket", objs)
+	m := manifest(inventoryBucketName, inventoryFilename)
+	m.FileSchema = "bucket, key, version_id, is_latest, is_delete_marker, size, last_modified_date, e_tag"
+
+	reader := NewInventoryReader(context.Background(), svc, logging.Default())
+	fileReader, err := reader.GetInventoryFileReader(m, inventoryFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make([]InventoryObject, 10)
+	if err := fileReader.Read(&res); err != nil {
+		t.Fatal(err)
+	}
+	_ = fileReader.Close()
+	if len(res) != 1 || res[0].Key != "foo" || res[0].VersionId == nil || *res[0].VersionId != "v2" {
+		t.Fatalf("expected only the latest, non-deleted version of foo, got %+v", res)
+	}
+
+	allVersionsReader := NewInventoryReader(context.Background(), svc, logging.Default(), WithAllVersions())
+	allFileReader, err := allVersionsReader.GetInventoryFileReader(m, inventoryFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = allFileReader.Close() }()
+	res = make([]InventoryObject, 10)
+	if err := allFileReader.Read(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(objs) {
+		t.Fatalf("expected all %d versions with WithAllVersions, got %d", len(objs), len(res))
+	}
+}
+
+// csvTestLastModifiedDate is a fixed last_modified_date in the ISO-8601
+// layout AWS's CSV inventory output actually uses (e.g.
+// "2016-11-06T00:00Z"), not a Unix epoch integer.
+const csvTestLastModifiedDate = "2016-11-06T00:00Z"
+
+func uploadCSVFile(t *testing.T, svc s3iface.S3API, inventoryBucket string, inventoryFilename string, destBucket string, keys ...string) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := csv.NewWriter(gz)
+	for _, k := range keys {
+		if err := w.Write([]string{destBucket, k, "500", csvTestLastModifiedDate, "abcdefg"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	uploader := s3manager.NewUploaderWithClient(svc)
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(inventoryBucket),
+		Key:    aws.String(inventoryFilename),
+		Body:   bytes.NewReader(buf.Bytes()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCSVInventoryReader(t *testing.T) {
+	svc, testServer := getS3Fake(t)
+	defer testServer.Close()
+	const inventoryBucketName = "csv-inventory-bucket"
+	const inventoryFilename = "inventory.csv.gz"
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(inventoryBucketName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"boo", "loo", "zoo"}
+	uploadCSVFile(t, svc, inventoryBucketName, inventoryFilename, "data-bucket", keys...)
+
+	m := manifest(inventoryBucketName, inventoryFilename)
+	m.Format = CSVFormatName
+	m.FileSchema = "bucket, key, size, last_modified_date, e_tag"
+
+	reader := NewInventoryReader(context.Background(), svc, logging.Default())
+	fileReader, err := reader.GetInventoryFileReader(m, inventoryFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = fileReader.Close() }()
+	res := make([]InventoryObject, 10)
+	if err := fileReader.Read(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(keys) {
+		t.Fatalf("expected %d rows, got %d", len(keys), len(res))
+	}
+	expectedLastModified, err := time.Parse(csvLastModifiedDateLayout, csvTestLastModifiedDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, k := range keys {
+		if res[i].Key != k {
+			t.Fatalf("result in index %d different than expected. expected=%s, got=%s", i, k, res[i].Key)
+		}
+		if res[i].LastModified == nil || *res[i].LastModified != expectedLastModified.Unix() {
+			t.Fatalf("result in index %d has unexpected LastModified. expected=%d, got=%+v", i, expectedLastModified.Unix(), res[i].LastModified)
+		}
+	}
+}
+
+func uploadParquetFile(t *testing.T, svc s3iface.S3API, inventoryBucket string, inventoryFilename string, destBucket string, keys ...string) {
+	fw, err := local.NewLocalFileWriter(filepath.Join(t.TempDir(), "inventory.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw, err := writer.NewParquetWriter(fw, new(parquetInventoryObject), parquetReaderParallelism)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		o := parquetInventoryObject{Bucket: destBucket, Key: k, Checksum: swag.String("abcdefg")}
+		if err := pw.Write(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadFile(fw.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader := s3manager.NewUploaderWithClient(svc)
+	_, err = uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(inventoryBucket),
+		Key:    aws.String(inventoryFilename),
+		Body:   bytes.NewReader(body),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestParquetInventoryReader guards against regressions
+// where MinValue/MaxValue skip relative to each other instead of from a
+// fixed start: it calls both on the same metadata reader, which is exactly
+// how sortManifest uses it.
+func TestParquetInventoryReader(t *testing.T) {
+	svc, testServer := getS3Fake(t)
+	defer testServer.Close()
+	const inventoryBucketName = "parquet-inventory-bucket"
+	const inventoryFilename = "inventory.parquet"
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(inventoryBucketName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"boo", "loo", "zoo"}
+	uploadParquetFile(t, svc, inventoryBucketName, inventoryFilename, "data-bucket", keys...)
+
+	m := manifest(inventoryBucketName, inventoryFilename)
+	m.Format = ParquetFormatName
+
+	reader := NewInventoryReader(context.Background(), svc, logging.Default())
+	fileReader, err := reader.GetInventoryFileReader(m, inventoryFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make([]InventoryObject, 10)
+	if err := fileReader.Read(&res); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileReader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != len(keys) {
+		t.Fatalf("expected %d rows, got %d", len(keys), len(res))
+	}
+	for i, k := range keys {
+		if res[i].Key != k {
+			t.Fatalf("result in index %d different than expected. expected=%s, got=%s", i, k, res[i].Key)
+		}
+	}
+
+	metaReader, err := reader.GetInventoryMetadataReader(m, inventoryFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = metaReader.Close() }()
+	minValue, maxValue := metaReader.MinValue(), metaReader.MaxValue()
+	if minValue != "boo" {
+		t.Fatalf("expected MinValue=boo, got %s", minValue)
+	}
+	if maxValue != "zoo" {
+		t.Fatalf("expected MaxValue=zoo, got %s", maxValue)
+	}
+}
+
+func TestManifestChecksum(t *testing.T) {
+	svc, testServer := getS3Fake(t)
+	defer testServer.Close()
+	const bucketName = "manifest-bucket"
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manifest{
+		InventoryBucketArn: "arn:aws:s3:::" + bucketName,
+		SourceBucket:       "data-bucket",
+		Files:              []inventoryFile{{Key: "inventoryFile.orc"}},
+		Format:             OrcFormatName,
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader := s3manager.NewUploaderWithClient(svc)
+	_, err = uploader.Upload(&s3manager.UploadInput{Bucket: aws.String(bucketName), Key: aws.String("manifest.json"), Body: bytes.NewReader(body)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := md5.Sum(body) //nolint:gosec // not used for security purposes, only to match S3's own checksum format
+	_, err = uploader.Upload(&s3manager.UploadInput{Bucket: aws.String(bucketName), Key: aws.String("manifest.checksum"), Body: bytes.NewReader([]byte(hex.EncodeToString(sum[:])))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadManifest(fmt.Sprintf("s3://%s/manifest.json", bucketName), svc, nil); err != nil {
+		t.Fatalf("expected manifest with a matching checksum to load, got: %v", err)
+	}
+
+	_, err = uploader.Upload(&s3manager.UploadInput{Bucket: aws.String(bucketName), Key: aws.String("manifest.checksum"), Body: bytes.NewReader([]byte("not-the-real-checksum"))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadManifest(fmt.Sprintf("s3://%s/manifest.json", bucketName), svc, nil); err == nil {
+		t.Fatal("expected loadManifest to fail on a checksum mismatch")
+	}
+}
+
+// TestManifestChecksumMissing covers S3-compatible sources (MinIO, Ceph
+// RGW, ...) that don't publish an AWS-style manifest.checksum sibling:
+// loadManifest should skip verification rather than fail, the same way
+// getInventoryObject already treats a missing per-file MD5checksum.
+func TestManifestChecksumMissing(t *testing.T) {
+	svc, testServer := getS3Fake(t)
+	defer testServer.Close()
+	const bucketName = "manifest-bucket-no-checksum"
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manifest{
+		InventoryBucketArn: "arn:aws:s3:::" + bucketName,
+		SourceBucket:       "data-bucket",
+		Files:              []inventoryFile{{Key: "inventoryFile.orc"}},
+		Format:             OrcFormatName,
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader := s3manager.NewUploaderWithClient(svc)
+	_, err = uploader.Upload(&s3manager.UploadInput{Bucket: aws.String(bucketName), Key: aws.String("manifest.json"), Body: bytes.NewReader(body)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadManifest(fmt.Sprintf("s3://%s/manifest.json", bucketName), svc, nil); err != nil {
+		t.Fatalf("expected manifest with no manifest.checksum sibling to load, got: %v", err)
+	}
+}
+
+func TestParseManifestSchema(t *testing.T) {
+	cases := []struct {
+		name       string
+		fileSchema string
+		expected   []string
+	}{
+		{name: "empty falls back to legacy columns", fileSchema: "", expected: defaultInventoryColumns},
+		{
+			// The casing AWS actually publishes in a real manifest.json's
+			// fileSchema field.
+			name:       "aws pascal case",
+			fileSchema: "Bucket, Key, VersionId, IsLatest, IsDeleteMarker, Size, LastModifiedDate, ETag, StorageClass, EncryptionStatus",
+			expected: []string{
+				"bucket", "key", "version_id", "is_latest", "is_delete_marker",
+				"size", "last_modified_date", "e_tag", "storage_class", "encryption_status",
+			},
+		},
+		{
+			name:       "already snake_case passes through",
+			fileSchema: "bucket, key, version_id, is_latest, is_delete_marker, size, last_modified_date, e_tag",
+			expected:   []string{"bucket", "key", "version_id", "is_latest", "is_delete_marker", "size", "last_modified_date", "e_tag"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := parseManifestSchema(c.fileSchema)
+			if len(got) != len(c.expected) {
+				t.Fatalf("expected=%v, got=%v", c.expected, got)
+			}
+			for i := range got {
+				if got[i] != c.expected[i] {
+					t.Fatalf("expected=%v, got=%v", c.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInventoryBucketName(t *testing.T) {
+	cases := []struct {
+		name              string
+		destinationBucket string
+		expected          string
+		expectErr         bool
+	}{
+		{name: "aws arn", destinationBucket: "arn:aws:s3:::my-bucket", expected: "my-bucket"},
+		{name: "aws-cn arn", destinationBucket: "arn:aws-cn:s3:::my-bucket", expected: "my-bucket"},
+		{name: "aws-us-gov arn", destinationBucket: "arn:aws-us-gov:s3:::my-bucket", expected: "my-bucket"},
+		{name: "bare bucket name", destinationBucket: "my-bucket", expected: "my-bucket"},
+		{name: "empty", destinationBucket: "", expectErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := inventoryBucketName(c.destinationBucket)
+			if c.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.expected {
+				t.Fatalf("expected=%s, got=%s", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestInventorySourceConfig(t *testing.T) {
+	cfg := &InventorySourceConfig{
+		Endpoint:         "http://minio.local:9000",
+		Region:           "us-east-1",
+		AccessKeyID:      "minio",
+		SecretAccessKey:  "minio123",
+		S3ForcePathStyle: true,
+	}
+	if _, err := cfg.buildS3Client(); err != nil {
+		t.Fatalf("expected a default (v4) signature version to build a client, got: %v", err)
+	}
+
+	cfg.SignatureVersion = "v2"
+	if _, err := cfg.buildS3Client(); err == nil {
+		t.Fatal("expected an unsupported signature version to fail")
+	}
+}
+
+func TestInventoryReaderStorageClassAndEncryption(t *testing.T) {
+	svc, testServer := getS3Fake(t)
+	defer testServer.Close()
+	const inventoryBucketName = "encrypted-inventory-bucket"
+	const inventoryFilename = "inventory.csv.gz"
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(inventoryBucketName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := csv.NewWriter(gz)
+	if err := w.Write([]string{"data-bucket", "boo", "GLACIER", "SSE-KMS"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	uploader := s3manager.NewUploaderWithClient(svc)
+	_, err = uploader.Upload(&s3manager.UploadInput{Bucket: aws.String(inventoryBucketName), Key: aws.String(inventoryFilename), Body: bytes.NewReader(buf.Bytes())})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := manifest(inventoryBucketName, inventoryFilename)
+	m.Format = CSVFormatName
+	m.FileSchema = "bucket, key, storage_class, encryption_status"
+
+	reader := NewInventoryReader(context.Background(), svc, logging.Default())
+	fileReader, err := reader.GetInventoryFileReader(m, inventoryFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = fileReader.Close() }()
+	res := make([]InventoryObject, 10)
+	if err := fileReader.Read(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].StorageClass == nil || *res[0].StorageClass != "GLACIER" {
+		t.Fatalf("expected storage_class=GLACIER, got %+v", res)
+	}
+	if res[0].EncryptionStatus == nil || *res[0].EncryptionStatus != "SSE-KMS" {
+		t.Fatalf("expected encryption_status=SSE-KMS, got %+v", res)
+	}
+}
+
+func TestSSECustomerKeyConfigApply(t *testing.T) {
+	var nilConfig *sseCustomerKeyConfig
+	input := nilConfig.apply(&s3.GetObjectInput{})
+	if input.SSECustomerAlgorithm != nil || input.SSECustomerKey != nil {
+		t.Fatal("expected a nil config to leave the input untouched")
+	}
+
+	cfg := &sseCustomerKeyConfig{Algorithm: "AES256", Key: "secret-key"}
+	input = cfg.apply(&s3.GetObjectInput{})
+	if input.SSECustomerAlgorithm == nil || *input.SSECustomerAlgorithm != "AES256" {
+		t.Fatalf("expected SSECustomerAlgorithm to be set, got %+v", input.SSECustomerAlgorithm)
+	}
+	if input.SSECustomerKey == nil || *input.SSECustomerKey != "secret-key" {
+		t.Fatalf("expected SSECustomerKey to be set, got %+v", input.SSECustomerKey)
+	}
+
+	reader := NewInventoryReader(context.Background(), nil, logging.Default(), WithSSECustomerKey("AES256", "secret-key")).(*InventoryReader)
+	if reader.sseConfig() == nil || reader.sseConfig().Algorithm != "AES256" {
+		t.Fatalf("expected WithSSECustomerKey to configure the reader's sse settings, got %+v", reader.sseConfig())
+	}
+}
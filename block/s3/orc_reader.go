@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/scritchley/orc"
+)
+
+// orcReader implements InventoryFileReader/InventoryMetadataReader over an
+// ORC-formatted inventory file. The set of columns it decodes is driven by
+// the manifest's fileSchema rather than a fixed struct, so it works for
+// both the legacy and versioned inventory schemas.
+type orcReader struct {
+	file    *os.File
+	reader  *orc.Reader
+	cursor  *orc.Cursor
+	columns []string
+
+	minMaxScanned bool
+	minValue      string
+	maxValue      string
+}
+
+func newOrcReader(ctx context.Context, svc s3iface.S3API, m *Manifest, key string, sse *sseCustomerKeyConfig) (*orcReader, error) {
+	body, err := getInventoryObject(ctx, svc, m, key, sse)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := ioutil.TempFile("", "inventory-orc")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(body); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to buffer inventory file %s: %w", key, err)
+	}
+
+	r, err := orc.Open(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to open orc inventory file %s: %w", key, err)
+	}
+	columns := parseManifestSchema(m.FileSchema)
+	return &orcReader{file: f, reader: r, cursor: r.Select(columns...), columns: columns}, nil
+}
+
+func (o *orcReader) GetNumRows() int64 {
+	return int64(o.reader.NumRows())
+}
+
+func (o *orcReader) SkipRows(n int64) error {
+	for i := int64(0); i < n; i++ {
+		if !o.cursor.Next() {
+			return o.cursor.Err()
+		}
+	}
+	return nil
+}
+
+func (o *orcReader) MinValue() string {
+	o.scanMinMax()
+	return o.minValue
+}
+
+func (o *orcReader) MaxValue() string {
+	o.scanMinMax()
+	return o.maxValue
+}
+
+// scanMinMax consumes the cursor once to learn the file's first and last
+// key. S3 Inventory files are internally sorted by key, so this also
+// serves as the file's key range used by sortManifest.
+func (o *orcReader) scanMinMax() {
+	if o.minMaxScanned {
+		return
+	}
+	o.minMaxScanned = true
+	keyIdx := indexOfColumn(o.columns, "key")
+	if keyIdx < 0 {
+		return
+	}
+	for o.cursor.Next() {
+		row := o.cursor.Row()
+		if keyIdx >= len(row) {
+			continue
+		}
+		key, _ := row[keyIdx].(string)
+		if o.minValue == "" {
+			o.minValue = key
+		}
+		o.maxValue = key
+	}
+}
+
+func (o *orcReader) Read(dstInterface interface{}) error {
+	dst, ok := dstInterface.(*[]InventoryObject)
+	if !ok {
+		return fmt.Errorf("unsupported destination type: %T", dstInterface)
+	}
+	batchSize := len(*dst)
+	rows := make([]InventoryObject, 0, batchSize)
+	for len(rows) < batchSize && o.cursor.Next() {
+		row := o.cursor.Row()
+		var obj InventoryObject
+		for i, col := range o.columns {
+			if i < len(row) {
+				setInventoryObjectField(&obj, col, row[i])
+			}
+		}
+		rows = append(rows, obj)
+	}
+	*dst = rows
+	return o.cursor.Err()
+}
+
+func (o *orcReader) Close() error {
+	cerr := o.reader.Close()
+	name := o.file.Name()
+	ferr := o.file.Close()
+	_ = os.Remove(name)
+	if cerr != nil {
+		return cerr
+	}
+	return ferr
+}
+
+func indexOfColumn(columns []string, name string) int {
+	for i, c := range columns {
+		if c == name {
+			return i
+		}
+	}
+	return -1
+}
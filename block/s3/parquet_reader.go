@@ -0,0 +1,176 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// bufferParquetFile implements source.ParquetFile over an in-memory buffer,
+// so inventory files can be checksum-verified with getInventoryObject
+// before parquet-go ever reads a byte. parquet-go opens a fresh handle per
+// parallel reader goroutine via Open, so every handle shares the same
+// underlying body and just gets its own read offset.
+type bufferParquetFile struct {
+	*bytes.Reader
+	body []byte
+}
+
+func newBufferParquetFile(body []byte) *bufferParquetFile {
+	return &bufferParquetFile{Reader: bytes.NewReader(body), body: body}
+}
+
+func (f *bufferParquetFile) Write(_ []byte) (int, error) {
+	return 0, errors.New("bufferParquetFile is read-only")
+}
+
+func (f *bufferParquetFile) Close() error { return nil }
+
+func (f *bufferParquetFile) Open(string) (source.ParquetFile, error) {
+	return newBufferParquetFile(f.body), nil
+}
+
+func (f *bufferParquetFile) Create(string) (source.ParquetFile, error) {
+	return nil, errors.New("bufferParquetFile is read-only")
+}
+
+var _ io.Seeker = (*bufferParquetFile)(nil)
+
+// parquetInventoryObject mirrors InventoryObject with the struct tags
+// parquet-go needs to bind columns by name. Columns absent from a given
+// file (e.g. a legacy, unversioned inventory) are left as their zero
+// value, since every versioning-related field is optional.
+type parquetInventoryObject struct {
+	Bucket           string  `parquet:"name=bucket, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Key              string  `parquet:"name=key, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Size             *int64  `parquet:"name=size, type=INT64, repetitiontype=OPTIONAL"`
+	LastModified     *int64  `parquet:"name=last_modified_date, type=INT64, repetitiontype=OPTIONAL"`
+	Checksum         *string `parquet:"name=e_tag, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	VersionId        *string `parquet:"name=version_id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	IsLatest         *bool   `parquet:"name=is_latest, type=BOOLEAN, repetitiontype=OPTIONAL"`
+	IsDeleteMarker   *bool   `parquet:"name=is_delete_marker, type=BOOLEAN, repetitiontype=OPTIONAL"`
+	SequencerId      *string `parquet:"name=sequencer, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	StorageClass     *string `parquet:"name=storage_class, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	EncryptionStatus *string `parquet:"name=encryption_status, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+func (p parquetInventoryObject) toInventoryObject() InventoryObject {
+	return InventoryObject{
+		Bucket:           p.Bucket,
+		Key:              p.Key,
+		Size:             p.Size,
+		LastModified:     p.LastModified,
+		Checksum:         p.Checksum,
+		VersionId:        p.VersionId,
+		IsLatest:         p.IsLatest,
+		IsDeleteMarker:   p.IsDeleteMarker,
+		SequencerId:      p.SequencerId,
+		StorageClass:     p.StorageClass,
+		EncryptionStatus: p.EncryptionStatus,
+	}
+}
+
+const parquetReaderParallelism = 4
+
+type parquetReader struct {
+	file   *bufferParquetFile
+	reader *reader.ParquetReader
+
+	minMaxScanned bool
+	minValue      string
+	maxValue      string
+}
+
+func newParquetReader(ctx context.Context, svc s3iface.S3API, m *Manifest, key string, sse *sseCustomerKeyConfig) (*parquetReader, error) {
+	body, err := getInventoryObject(ctx, svc, m, key, sse)
+	if err != nil {
+		return nil, err
+	}
+	file := newBufferParquetFile(body)
+	pr, err := reader.NewParquetReader(file, new(parquetInventoryObject), parquetReaderParallelism)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet inventory file %s: %w", key, err)
+	}
+	return &parquetReader{file: file, reader: pr}, nil
+}
+
+func (p *parquetReader) GetNumRows() int64 {
+	return p.reader.GetNumRows()
+}
+
+func (p *parquetReader) SkipRows(n int64) error {
+	return p.reader.SkipRows(n)
+}
+
+func (p *parquetReader) MinValue() string {
+	p.scanMinMax()
+	return p.minValue
+}
+
+func (p *parquetReader) MaxValue() string {
+	p.scanMinMax()
+	return p.maxValue
+}
+
+// scanMinMax reads the file's first key and seeks straight to its last
+// key, memoizing both. S3 Inventory files are internally sorted by key, so
+// this also serves as the file's key range used by sortManifest. Reading
+// row 0 leaves the cursor at row 1, so the seek to the last row only needs
+// to skip numRows-2 more rows, not decode every row in between -
+// parquet-go's SkipRows is relative to the current cursor, not an
+// absolute row index, so the skip distance has to account for that offset
+// rather than being numRows-1 as if starting from row 0.
+func (p *parquetReader) scanMinMax() {
+	if p.minMaxScanned {
+		return
+	}
+	p.minMaxScanned = true
+	numRows := p.reader.GetNumRows()
+	if numRows == 0 {
+		return
+	}
+	rows := make([]parquetInventoryObject, 1)
+	if err := p.reader.Read(&rows); err != nil || len(rows) == 0 {
+		return
+	}
+	p.minValue = rows[0].Key
+	p.maxValue = rows[0].Key
+	if numRows == 1 {
+		return
+	}
+	if err := p.reader.SkipRows(numRows - 2); err != nil {
+		return
+	}
+	if err := p.reader.Read(&rows); err != nil || len(rows) == 0 {
+		return
+	}
+	p.maxValue = rows[0].Key
+}
+
+func (p *parquetReader) Read(dstInterface interface{}) error {
+	dst, ok := dstInterface.(*[]InventoryObject)
+	if !ok {
+		return fmt.Errorf("unsupported destination type: %T", dstInterface)
+	}
+	rows := make([]parquetInventoryObject, len(*dst))
+	if err := p.reader.Read(&rows); err != nil {
+		return err
+	}
+	objects := make([]InventoryObject, len(rows))
+	for i, row := range rows {
+		objects[i] = row.toInventoryObject()
+	}
+	*dst = objects
+	return nil
+}
+
+func (p *parquetReader) Close() error {
+	p.reader.ReadStop()
+	return p.file.Close()
+}